@@ -0,0 +1,104 @@
+// Package complexity는 인코딩 전에 짧은 샘플 프레임을 디코드해 프레임 사이의 화면
+// 변화량(휘도 차이)을 가늠하는 "복잡도 점수"를 계산합니다. 이 점수는 인코더의
+// CRF/프리셋을 파일별로 자동 조정하는 데 쓰입니다.
+//
+// 주의: 이 패키지는 실제 모션 벡터(AV_FRAME_DATA_MOTION_VECTORS)를 분석하지 않습니다.
+// signalstats 필터의 프레임 간 평균 휘도 차이(YDIF)를 움직임의 대리 지표로 사용하는
+// 것으로, 그레인/노이즈/장면 전환도 값을 높여 실제 모션과 혼동될 수 있는 한계가 있습니다.
+package complexity
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// maxSampledFrames은 한 번의 분석에서 측정할 최대 샘플 프레임 수입니다. "-frames:v"로
+// select 필터를 통과한 출력 프레임 수를 여기서 제한하면 ffmpeg가 그만큼만 내보낸 뒤 입력
+// 읽기를 멈추므로, 디코드되는 프레임은 최대 maxSampledFrames*sampleEvery개 정도로 제한되어
+// select 필터만으로는 막지 못하는 "영상 전체 디코드"를 방지합니다.
+const maxSampledFrames = 90
+
+// ydifPattern은 signalstats 필터가 metadata=print로 찍는 프레임당 휘도 변화량
+// (이전 프레임과의 평균 절대 차이, 0~255)을 찾습니다. signalstats/metadata=print는
+// ffmpeg 공식 필터로 출력 형식이 안정적으로 문서화되어 있어, 빌드/코덱에 따라 형식이
+// 달라질 수 있는 "-debug +mv" 텍스트 덤프를 파싱하는 것보다 신뢰할 수 있습니다.
+var ydifPattern = regexp.MustCompile(`lavfi\.signalstats\.YDIF=([0-9]+(?:\.[0-9]+)?)`)
+
+// Score는 샘플링된 프레임들에 대한 프레임 간 화면 변화 분석 결과입니다.
+type Score struct {
+	// AverageDiff는 샘플링된 프레임 사이의 평균 휘도 변화량(signalstats YDIF, 0~255)입니다.
+	// 값이 클수록 프레임 간 화면 변화가 크다는 뜻이며, 실제 모션 벡터 크기가 아닙니다.
+	AverageDiff float64
+	// SampledFrames는 분석에 사용된 프레임 수입니다.
+	SampledFrames int
+}
+
+// Analyze는 ffmpeg로 inputPath의 매 sampleEvery번째 프레임만 골라 signalstats 필터로
+// 프레임 간 휘도 변화량을 측정해 Score를 계산합니다. sampleEvery가 1 미만이면 30으로
+// 보정됩니다. 측정은 최대 maxSampledFrames개의 샘플 프레임(디코드는 최대 약
+// maxSampledFrames*sampleEvery 프레임)으로 제한되므로, 영상 길이와 무관하게 끝납니다.
+// ffmpeg가 정상 종료했는데 측정값이 전혀 나오지 않으면(예: 샘플링된 프레임이 없는 경우)
+// 오류 없이 빈 Score를 반환하므로, 호출자는 AutoTune을 건너뛸지 판단할 수 있습니다.
+// ffmpeg 자체가 실패하면(예: 이 빌드에 signalstats 필터가 없는 경우) 오류를 반환합니다.
+func Analyze(ctx context.Context, ffmpegPath string, inputPath string, sampleEvery int) (Score, error) {
+	if sampleEvery < 1 {
+		sampleEvery = 30
+	}
+
+	selectExpr := fmt.Sprintf("not(mod(n\\,%d))", sampleEvery)
+	filterChain := "select='" + selectExpr + "',signalstats,metadata=print:file=-"
+
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-an",
+		"-i", inputPath,
+		"-vf", filterChain,
+		"-frames:v", strconv.Itoa(maxSampledFrames),
+		"-f", "null",
+		"-",
+	)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return Score{}, fmt.Errorf("복잡도 분석 파이프 생성 중 오류 발생 (파일: %s): %w", inputPath, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return Score{}, fmt.Errorf("복잡도 분석용 ffmpeg 실행 중 오류 발생 (파일: %s): %w", inputPath, err)
+	}
+
+	var totalMagnitude float64
+	var sampledFrames int
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		match := ydifPattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		magnitude, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			continue
+		}
+		totalMagnitude += magnitude
+		sampledFrames++
+	}
+
+	runErr := cmd.Wait()
+	if runErr != nil {
+		return Score{}, fmt.Errorf("복잡도 분석용 ffmpeg 실행 중 오류 발생 (파일: %s): %w", inputPath, runErr)
+	}
+
+	if sampledFrames == 0 {
+		return Score{}, nil
+	}
+
+	return Score{
+		AverageDiff:   totalMagnitude / float64(sampledFrames),
+		SampledFrames: sampledFrames,
+	}, nil
+}
@@ -1,21 +1,93 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Chahangyu/convert_video_to_av1/complexity"
+	"github.com/Chahangyu/convert_video_to_av1/converter"
+	"github.com/Chahangyu/convert_video_to_av1/encoder"
+	"github.com/Chahangyu/convert_video_to_av1/ffprobe"
+	"github.com/Chahangyu/convert_video_to_av1/output"
+	"github.com/Chahangyu/convert_video_to_av1/progress"
 )
 
 type Config struct {
 	BasePath   string `json:"BasePath"`
 	FfmpegPath string `json:"FfmpegPath"`
+	// Workers는 동시에 실행할 ffmpeg 프로세스 수입니다. 0이면 defaultWorkers()로 결정됩니다.
+	Workers int `json:"Workers"`
+	// Encoder는 사용할 AV1 인코더 백엔드입니다 (예: "av1_qsv", "libsvtav1", "libaom-av1",
+	// "av1_nvenc", "av1_amf"). 비어 있으면 기본값 "av1_qsv"를 사용합니다.
+	Encoder string `json:"Encoder"`
+	// FallbackEncoder는 Encoder가 이 ffmpeg 빌드에 컴파일되어 있지 않을 때 대신 사용할 인코더입니다.
+	FallbackEncoder string `json:"FallbackEncoder"`
+	// EncoderOptions는 선택된 인코더에 전달할 CRF/프리셋 등의 파라미터입니다.
+	EncoderOptions encoder.Options `json:"EncoderOptions"`
+	// ProgressLogSeconds는 진행 상황을 로그로 남기는 최소 간격(초)입니다. 0이면 기본값 10을 사용합니다.
+	ProgressLogSeconds int `json:"ProgressLogSeconds"`
+	// AutoTune이 활성화되면 인코딩 전에 complexity.Analyze로 프레임 변화량 기반 복잡도를
+	// 분석해 파일별로 CRF/프리셋을 조정합니다.
+	AutoTune AutoTune `json:"AutoTune"`
+	// ReplaceOriginal이 true이면 검증을 통과한 변환 결과물로 원본 소스 파일을 대체합니다(삭제).
+	ReplaceOriginal bool `json:"ReplaceOriginal"`
+	// VerifyToleranceFraction은 출력물 길이(길이를 알 수 없으면 프레임 수)가 원본과 이 비율을
+	// 넘게 차이나면 검증 실패로 봅니다.
+	// 0이면 기본값 0.02(2%)를 사용합니다.
+	VerifyToleranceFraction float64 `json:"VerifyToleranceFraction"`
+	// StateFilePath는 이미 변환된 파일을 기록하는 JSON 상태 파일의 경로입니다.
+	// 비어 있으면 BasePath 안의 ".convert_state.json"을 사용합니다.
+	StateFilePath string `json:"StateFilePath"`
+}
+
+// AutoTune은 complexity.Analyze가 계산한 프레임 변화 기반 복잡도 점수(Score.AverageDiff,
+// 모션 벡터가 아니라 signalstats YDIF 휘도 차이입니다)를 바탕으로 인코더 설정을 자동
+// 조정하기 위한 임계값과 대상 값들입니다.
+type AutoTune struct {
+	Enabled bool `json:"Enabled"`
+	// SampleInterval은 복잡도 분석 시 몇 프레임마다 하나씩 샘플링할지입니다. 0이면 30을 사용합니다.
+	SampleInterval int `json:"SampleInterval"`
+	// HighComplexityThreshold 이상이면 고복잡도로 판단해 HighComplexityCRF/Preset을 사용합니다.
+	// complexity.Score.AverageDiff(signalstats YDIF, 0~255 스케일의 평균 휘도 변화량)와
+	// 비교되는 값입니다.
+	HighComplexityThreshold float64 `json:"HighComplexityThreshold"`
+	// LowComplexityThreshold 이하면 저복잡도(토킹헤드, 화면 녹화 등)로 판단합니다.
+	// HighComplexityThreshold와 마찬가지로 YDIF 스케일(0~255) 기준입니다.
+	LowComplexityThreshold float64 `json:"LowComplexityThreshold"`
+	HighComplexityCRF      int     `json:"HighComplexityCRF"`
+	HighComplexityPreset   string  `json:"HighComplexityPreset"`
+	LowComplexityCRF       int     `json:"LowComplexityCRF"`
+	LowComplexityPreset    string  `json:"LowComplexityPreset"`
+}
+
+// defaultWorkers는 Workers가 설정되지 않았을 때 사용할 기본 동시성을 결정합니다.
+// av1_qsv는 GPU의 디코더/인코더 세션을 공유하므로 여러 개를 동시에 돌려도 GPU 단에서
+// 직렬화되어 오버헤드만 늘어나기 때문에 1로 고정하고, 그 외 소프트웨어/다른 하드웨어
+// 인코더는 CPU 코어 수를 바탕으로 병렬도를 정합니다.
+func defaultWorkers(encoderName string) int {
+	if strings.EqualFold(encoderName, "av1_qsv") || strings.EqualFold(encoderName, "qsv") {
+		return 1
+	}
+	if n := runtime.NumCPU() / 2; n > 1 {
+		return n
+	}
+	return 1
 }
 
 var videoExtensions = map[string]bool{
@@ -61,6 +133,58 @@ func loadConfig(filename string) (Config, error) {
 		log.Printf("경고: FfmpegPath '%s' 파일은 존재하지만 실행 가능한 상태인지 확인하지 못했습니다. PATH 또는 권한 문제를 확인하세요.", config.FfmpegPath)
 	}
 
+	if config.Encoder == "" {
+		log.Println("'Encoder'가 설정 파일에 지정되지 않았습니다. 기본값 'av1_qsv'를 사용합니다.")
+		config.Encoder = "av1_qsv"
+	}
+
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers(config.Encoder)
+		log.Printf("'Workers'가 설정 파일에 지정되지 않았습니다. 기본값 %d를 사용합니다.", config.Workers)
+	}
+
+	if config.ProgressLogSeconds <= 0 {
+		config.ProgressLogSeconds = 10
+	}
+
+	if config.AutoTune.Enabled {
+		if config.AutoTune.SampleInterval <= 0 {
+			config.AutoTune.SampleInterval = 30
+		}
+		// 기본값은 complexity.Analyze가 반환하는 YDIF 기반 Score(0~255 스케일)에 맞춘 값입니다.
+		if config.AutoTune.HighComplexityThreshold <= 0 {
+			config.AutoTune.HighComplexityThreshold = 15
+		}
+		if config.AutoTune.LowComplexityThreshold <= 0 {
+			config.AutoTune.LowComplexityThreshold = 2
+		}
+		// CRF/Preset 대상 값이 비어 있으면 tunedOptions가 encoder.Options의 CRF=0,
+		// Preset=""을 그대로 돌려주고, encoder.Args가 이를 각 인코더의 기본값으로
+		// 되돌려버려 AutoTune이 조용히 아무 효과도 내지 못합니다. 고복잡도는 속도를
+		// 우선해 빠른 프리셋으로, 저복잡도는 여유가 있으니 느린 프리셋 + 높은 CRF로
+		// 기본값을 채워 항상 튜닝이 실제로 적용되게 합니다.
+		if config.AutoTune.HighComplexityCRF <= 0 {
+			config.AutoTune.HighComplexityCRF = 26
+		}
+		if config.AutoTune.HighComplexityPreset == "" {
+			config.AutoTune.HighComplexityPreset = "8"
+		}
+		if config.AutoTune.LowComplexityCRF <= 0 {
+			config.AutoTune.LowComplexityCRF = 34
+		}
+		if config.AutoTune.LowComplexityPreset == "" {
+			config.AutoTune.LowComplexityPreset = "4"
+		}
+	}
+
+	if config.VerifyToleranceFraction <= 0 {
+		config.VerifyToleranceFraction = 0.02
+	}
+
+	if config.StateFilePath == "" {
+		config.StateFilePath = filepath.Join(config.BasePath, ".convert_state.json")
+	}
+
 	return config, nil
 }
 
@@ -136,52 +260,71 @@ func findVideoFiles(basePath string) ([]string, error) {
 	return allVideoFiles, nil
 }
 
-// 비디오 파일의 코덱 정보를 가져오는 함수 (ffprobe 사용)
-func getVideoCodec(filePath string, ffmpegPath string) (string, error) {
-	// ffprobe 경로 얻기 (ffmpeg 경로를 기반으로)
-	ffmpegDir := filepath.Dir(ffmpegPath)
-	ffprobePath := filepath.Join(ffmpegDir, "ffprobe")
-	
-	// Windows 환경의 경우 .exe 확장자 추가
-	if runtime.GOOS == "windows" {
-		ffprobePath += ".exe"
-	}
-	
-	// ffprobe 실행 파일 존재 여부 확인
-	if _, err := os.Stat(ffprobePath); os.IsNotExist(err) {
-		log.Printf("경고: ffprobe를 찾을 수 없습니다: %s, 시스템 PATH에서 찾기 시도", ffprobePath)
-		ffprobePath = "ffprobe"
-	}
-	
-	// ffprobe 명령어 실행하여 코덱 정보 추출
-	cmd := exec.Command(ffprobePath,
-		"-v", "error",
-		"-select_streams", "v:0",
-		"-show_entries", "stream=codec_name",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		filePath,
-	)
-
-	output, err := cmd.CombinedOutput()
+// durationMs는 ffprobe의 Format.Duration(초 단위 문자열)을 밀리초로 변환합니다.
+// 파싱할 수 없으면 0을 반환하며, 이 경우 진행률(Percent)은 계산되지 않습니다.
+func durationMs(info *ffprobe.FFProbeInfo) int64 {
+	if info == nil {
+		return 0
+	}
+	seconds, err := strconv.ParseFloat(info.Format.Duration, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(seconds * 1000)
+}
+
+// tunedOptions는 AutoTune이 활성화되어 있으면 inputPath의 복잡도 점수를 분석해 CRF/프리셋을
+// 조정한 encoder.Options를 반환합니다. 분석에 실패하거나 복잡도를 감지하지 못하면 원래
+// 설정을 그대로 사용합니다 (조용히 건너뜀 - 자동 튜닝은 부가 기능이지 필수 기능이 아니다).
+func tunedOptions(ctx context.Context, ffmpegPath, inputPath string, autoTune AutoTune, opts encoder.Options) encoder.Options {
+	if !autoTune.Enabled {
+		return opts
+	}
+
+	score, err := complexity.Analyze(ctx, ffmpegPath, inputPath, autoTune.SampleInterval)
 	if err != nil {
-		return "", fmt.Errorf("코덱 정보 추출 중 오류 발생: %w, 명령어: %s", err, cmd.String())
+		log.Printf("경고: 파일 '%s'의 복잡도 분석 중 오류 발생, 기본 인코더 설정을 사용합니다: %v", inputPath, err)
+		return opts
+	}
+	if score.SampledFrames == 0 {
+		log.Printf("복잡도 분석 결과가 없습니다 (파일: '%s'), 기본 인코더 설정을 사용합니다.", inputPath)
+		return opts
 	}
 
-	// 결과에서 공백 제거
-	codec := strings.TrimSpace(string(output))
-	log.Printf("파일 '%s'의 코덱: %s", filePath, codec)
+	switch {
+	case score.AverageDiff >= autoTune.HighComplexityThreshold:
+		opts.CRF = autoTune.HighComplexityCRF
+		opts.Preset = autoTune.HighComplexityPreset
+		log.Printf("복잡도 분석: 파일 '%s' 고복잡도(평균 변화량 %.2f) -> CRF=%d, Preset=%s", inputPath, score.AverageDiff, opts.CRF, opts.Preset)
+	case score.AverageDiff <= autoTune.LowComplexityThreshold:
+		opts.CRF = autoTune.LowComplexityCRF
+		opts.Preset = autoTune.LowComplexityPreset
+		log.Printf("복잡도 분석: 파일 '%s' 저복잡도(평균 변화량 %.2f) -> CRF=%d, Preset=%s", inputPath, score.AverageDiff, opts.CRF, opts.Preset)
+	default:
+		log.Printf("복잡도 분석: 파일 '%s' 평균 변화량 %.2f, 기본 인코더 설정을 사용합니다.", inputPath, score.AverageDiff)
+	}
 
-	return codec, nil
+	return opts
 }
 
-func convertVideoToAV1(inputPath string, ffmpegPath string) error {
-	// 파일의 현재 코덱 확인
-	codec, err := getVideoCodec(inputPath, ffmpegPath)
+// convertVideoToAV1은 단일 파일을 AV1으로 변환합니다. ctx가 취소되면 실행 중인 ffmpeg
+// 프로세스에 종료 시그널을 보내고 즉시 반환합니다. 이미 AV1인 파일이거나 state에 변환
+// 완료로 기록되어 있는 파일은 skipped=true로 보고합니다.
+// reporter는 ffmpeg의 -progress 출력을 파싱한 진행 이벤트를 전달받습니다.
+func convertVideoToAV1(ctx context.Context, inputPath string, ffmpegPath string, enc encoder.Encoder, encOpts encoder.Options, autoTune AutoTune, state *output.StateStore, replaceOriginal bool, verifyTolerance float64, reporter progress.ProgressReporter) (skipped bool, err error) {
+	srcStat, statErr := os.Stat(inputPath)
+	if statErr == nil && state.IsConverted(inputPath, srcStat.Size(), srcStat.ModTime().UnixNano()) {
+		log.Printf("스킵: 파일 '%s'는 이전 실행에서 이미 변환되었습니다 (재개).", inputPath)
+		return true, nil
+	}
+
+	// ffprobe로 파일의 스트림 정보를 확인
+	info, err := ffprobe.Probe(inputPath, ffmpegPath)
 	if err != nil {
-		log.Printf("경고: 파일 '%s'의 코덱을 확인할 수 없습니다, 변환을 진행합니다: %v", inputPath, err)
-	} else if strings.Contains(strings.ToLower(codec), "av1") {
+		log.Printf("경고: 파일 '%s'의 스트림 정보를 확인할 수 없습니다, 변환을 진행합니다: %v", inputPath, err)
+	} else if video, ok := info.PrimaryVideoStream(); ok && strings.Contains(strings.ToLower(video.CodecName), "av1") {
 		log.Printf("스킵: 파일 '%s'는 이미 AV1 코덱입니다. 변환이 필요하지 않습니다.", inputPath)
-		return nil
+		return true, nil
 	}
 
 	dir := filepath.Dir(inputPath)
@@ -189,34 +332,95 @@ func convertVideoToAV1(inputPath string, ffmpegPath string) error {
 	ext := filepath.Ext(baseName)
 	outputFileName := fmt.Sprintf("%s_av1.mkv", strings.TrimSuffix(baseName, ext))
 	fullOutputPath := filepath.Join(dir, outputFileName)
+	partialOutputPath := output.PartialPath(fullOutputPath)
+
+	log.Printf("변환 시작: '%s' -> '%s' (using %s, 인코더 %s)", inputPath, fullOutputPath, ffmpegPath, enc.Name())
 
-	log.Printf("변환 시작: '%s' -> '%s' (using %s)", inputPath, fullOutputPath, ffmpegPath)
+	encOpts = tunedOptions(ctx, ffmpegPath, inputPath, autoTune, encOpts)
 
-	cmd := exec.Command(ffmpegPath,
-		"-i", inputPath,
-		"-c:v", "av1_qsv",
-		"-c:a", "copy",
-		"-y",
-		fullOutputPath,
-	)
+	if encOpts.PixFmt == "" && info != nil {
+		if video, ok := info.PrimaryVideoStream(); ok {
+			if pixFmt := encoder.TenBitPixFmt(enc.Name(), video.PixFmt); pixFmt != "" {
+				encOpts.PixFmt = pixFmt
+				log.Printf("10비트 소스 감지: 파일 '%s' (원본 pix_fmt=%s) -> 출력 pix_fmt=%s", inputPath, video.PixFmt, pixFmt)
+			}
+		}
+	}
 
+	// 오디오/자막은 기본 스트림 선택(비디오/오디오 각 1개)에 맡기지 않고 모든 트랙을
+	// 명시적으로 매핑합니다 - 그래야 보조 오디오 트랙(해설, 다른 언어 더빙 등)과 그 언어
+	// 태그가 변환 후에도 그대로 남습니다. 자막은 없을 수도 있으므로 "?"로 선택적으로 둡니다.
+	args := []string{"-i", inputPath, "-progress", "pipe:2", "-nostats",
+		"-map", "0:v:0", "-map", "0:a", "-map", "0:s?",
+		"-c:v", enc.Name()}
+	args = append(args, enc.Args(encOpts)...)
+	// ".partial" 확장자로는 ffmpeg가 출력 포맷을 추측할 수 없으므로 mkv 컨테이너를 명시합니다.
+	args = append(args, "-c:a", "copy", "-c:s", "copy", "-f", "matroska", "-y", partialOutputPath)
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return false, fmt.Errorf("ffmpeg stderr 파이프 생성 중 오류 발생 (파일: %s): %w", inputPath, err)
+	}
 
 	log.Printf("실행할 FFmpeg 명령어: %s", cmd.String())
 
-	err = cmd.Run()
-	if err != nil {
-		errMsg := fmt.Sprintf("FFmpeg 실행 중 오류 발생 (파일: %s): %v", inputPath, err)
-		if exitError, ok := err.(*exec.ExitError); ok {
-			errMsg = fmt.Sprintf("%s, 종료 코드: %d, 에러 출력: %s", errMsg, exitError.ExitCode(), string(exitError.Stderr))
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("FFmpeg 실행 시작 중 오류 발생 (파일: %s): %w", inputPath, err)
+	}
+
+	var stderrTail bytes.Buffer
+	tee := io.TeeReader(stderrPipe, io.MultiWriter(os.Stderr, &stderrTail))
+
+	parseDone := make(chan struct{})
+	go func() {
+		defer close(parseDone)
+		if err := progress.Parse(tee, durationMs(info), func(ev progress.Event) {
+			reporter.Report(inputPath, ev)
+		}); err != nil {
+			log.Printf("경고: 파일 '%s'의 진행 상황 파싱 중 오류 발생: %v", inputPath, err)
+		}
+	}()
+
+	runErr := cmd.Wait()
+	<-parseDone
+
+	if ctx.Err() != nil {
+		log.Printf("취소됨: 파일 '%s' 변환이 중단되었습니다: %v", inputPath, ctx.Err())
+		return false, ctx.Err()
+	}
+	if runErr != nil {
+		os.Remove(partialOutputPath)
+		errMsg := fmt.Sprintf("FFmpeg 실행 중 오류 발생 (파일: %s): %v", inputPath, runErr)
+		if exitError, ok := runErr.(*exec.ExitError); ok {
+			errMsg = fmt.Sprintf("%s, 종료 코드: %d, 에러 출력: %s", errMsg, exitError.ExitCode(), stderrTail.String())
 		}
 		log.Println(errMsg)
-		return fmt.Errorf(errMsg)
+		return false, errors.New(errMsg)
+	}
+
+	if err := output.Finalize(ffmpegPath, inputPath, partialOutputPath, fullOutputPath, verifyTolerance); err != nil {
+		return false, fmt.Errorf("출력 검증 중 오류 발생 (파일: %s): %w", inputPath, err)
+	}
+
+	if srcStat != nil {
+		if markErr := state.MarkConverted(inputPath, srcStat.Size(), srcStat.ModTime().UnixNano(), fullOutputPath); markErr != nil {
+			log.Printf("경고: 파일 '%s'의 변환 상태 기록 중 오류 발생: %v", inputPath, markErr)
+		}
+	}
+
+	if replaceOriginal {
+		if err := output.ReplaceOriginal(inputPath); err != nil {
+			log.Printf("경고: 원본 '%s' 삭제 중 오류 발생: %v", inputPath, err)
+		} else {
+			log.Printf("원본 삭제 완료: '%s'", inputPath)
+		}
 	}
 
 	log.Printf("변환 완료: '%s'", fullOutputPath)
-	return nil
+	return false, nil
 }
 
 func main() {
@@ -239,27 +443,43 @@ func main() {
 		return
 	}
 
-	successCount := 0
-	errorCount := 0
-	skippedCount := 0
+	enc, err := encoder.Select(config.FfmpegPath, config.Encoder, config.FallbackEncoder)
+	if err != nil {
+		log.Fatalf("인코더 선택 실패: %v", err)
+	}
+	log.Printf("사용할 인코더: %s", enc.Name())
+
+	state, err := output.LoadStateStore(config.StateFilePath)
+	if err != nil {
+		log.Fatalf("변환 상태 파일 로드 실패: %v", err)
+	}
+
+	log.Printf("워커 %d개로 변환을 시작합니다.", config.Workers)
+
+	reporter := progress.NewLogReporter(time.Duration(config.ProgressLogSeconds) * time.Second)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pool := converter.NewPool(ctx, config.Workers, func(jobCtx context.Context, path string) (bool, error) {
+		return convertVideoToAV1(jobCtx, path, config.FfmpegPath, enc, config.EncoderOptions, config.AutoTune, state, config.ReplaceOriginal, config.VerifyToleranceFraction, reporter)
+	})
+
+	go func() {
+		<-ctx.Done()
+		log.Println("종료 신호를 받았습니다. 진행 중인 변환이 끝나거나 정리될 때까지 기다립니다...")
+	}()
+
 	for _, file := range videoFiles {
-		// 변환 전 코덱 확인
-		codec, checkErr := getVideoCodec(file, config.FfmpegPath)
-		if checkErr == nil && strings.Contains(strings.ToLower(codec), "av1") {
-			log.Printf("스킵: 파일 '%s'는 이미 AV1 코덱입니다.", file)
-			skippedCount++
-			continue
-		}
-		
-		err := convertVideoToAV1(file, config.FfmpegPath)
-		if err != nil {
-			log.Printf("파일 변환 실패: %s - 오류: %v", file, err)
-			errorCount++
-		} else {
-			successCount++
-		}
+		pool.Submit(file)
+	}
+
+	stats := pool.Wait()
+
+	for _, jobErr := range stats.Errors {
+		log.Printf("파일 변환 실패: %s - 오류: %v", jobErr.Path, jobErr.Err)
 	}
 
-	log.Printf("모든 작업 완료. 성공: %d, 실패: %d, 스킵(이미 AV1): %d", successCount, errorCount, skippedCount)
+	log.Printf("모든 작업 완료. 성공: %d, 실패: %d, 스킵(이미 AV1 또는 이전 실행에서 변환됨): %d", stats.Success, len(stats.Errors), stats.Skipped)
 	log.Println("프로그램 종료")
 }
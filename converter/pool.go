@@ -0,0 +1,115 @@
+// Package converter는 여러 비디오 파일을 동시에 변환하기 위한 워커 풀을 제공합니다.
+package converter
+
+import (
+	"context"
+	"sync"
+)
+
+// ConvertFunc는 실제 변환 작업을 수행하는 함수입니다.
+// ctx가 취소되면 진행 중인 ffmpeg 프로세스를 정리하고 가능한 빨리 반환해야 합니다.
+// skipped가 true이면 이미 AV1인 파일 등 변환이 필요 없어 건너뛴 경우를 의미합니다.
+type ConvertFunc func(ctx context.Context, path string) (skipped bool, err error)
+
+// JobError는 실패한 파일 경로와 원인을 함께 담습니다.
+type JobError struct {
+	Path string
+	Err  error
+}
+
+// Stats는 Wait이 반환하는 집계 결과입니다.
+type Stats struct {
+	Success int
+	Skipped int
+	Errors  []JobError
+}
+
+// Pool은 지정된 수의 워커 고루틴으로 변환 작업을 병렬 실행합니다.
+type Pool struct {
+	convert ConvertFunc
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	jobs chan string
+	wg   sync.WaitGroup
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewPool은 workers개의 워커로 동작하는 Pool을 생성합니다. workers가 1 미만이면 1로 보정됩니다.
+// parent가 취소되면 아직 시작하지 않은 작업은 건너뛰고, 실행 중인 작업에는 취소된 ctx가 전달됩니다.
+func NewPool(parent context.Context, workers int, convert ConvertFunc) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	p := &Pool{
+		convert: convert,
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(chan string, workers*2),
+	}
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+
+	return p
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+
+	for path := range p.jobs {
+		select {
+		case <-p.ctx.Done():
+			p.recordError(path, p.ctx.Err())
+			continue
+		default:
+		}
+
+		skipped, err := p.convert(p.ctx, path)
+		if err != nil {
+			p.recordError(path, err)
+			continue
+		}
+
+		p.mu.Lock()
+		if skipped {
+			p.stats.Skipped++
+		} else {
+			p.stats.Success++
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *Pool) recordError(path string, err error) {
+	p.mu.Lock()
+	p.stats.Errors = append(p.stats.Errors, JobError{Path: path, Err: err})
+	p.mu.Unlock()
+}
+
+// Submit은 변환할 파일 경로를 큐에 넣습니다. Shutdown 이후에는 호출하지 않아야 합니다.
+func (p *Pool) Submit(path string) {
+	p.jobs <- path
+}
+
+// Shutdown은 진행 중인 작업에 전달되는 ctx를 취소합니다. 이미 실행 중인 ffmpeg 프로세스는
+// exec.CommandContext를 통해 종료 시그널을 받게 됩니다.
+func (p *Pool) Shutdown() {
+	p.cancel()
+}
+
+// Wait은 더 이상 제출할 작업이 없을 때 호출합니다. 큐를 닫고 모든 워커가 끝날 때까지
+// 대기한 뒤 집계된 Stats를 반환합니다.
+func (p *Pool) Wait() Stats {
+	close(p.jobs)
+	p.wg.Wait()
+	p.cancel()
+	return p.stats
+}
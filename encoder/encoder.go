@@ -0,0 +1,220 @@
+// Package encoder는 ffmpeg의 여러 AV1 인코더 백엔드(소프트웨어/하드웨어)에 대해
+// 공통 인터페이스와 ffmpeg 인자 생성을 제공합니다.
+package encoder
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Options는 사용자가 설정 파일에서 조정할 수 있는 인코딩 파라미터입니다.
+// 모든 필드가 모든 인코더에 적용되는 것은 아니며, 각 Encoder 구현이 자신에게
+// 의미 있는 필드만 ffmpeg 인자로 반영합니다.
+type Options struct {
+	CRF       int      `json:"CRF"`
+	Bitrate   string   `json:"Bitrate"`
+	Preset    string   `json:"Preset"`
+	Tune      string   `json:"Tune"`
+	PixFmt    string   `json:"PixFmt"`
+	ExtraArgs []string `json:"ExtraArgs"`
+}
+
+// Encoder는 하나의 AV1 인코더 백엔드를 나타냅니다.
+type Encoder interface {
+	// Name은 ffmpeg -c:v에 전달할 코덱 이름입니다 (예: "av1_qsv").
+	Name() string
+	// Args는 Options를 바탕으로 "-c:v" 뒤에 이어붙일 ffmpeg 인자 목록을 만듭니다.
+	Args(opts Options) []string
+}
+
+// registry는 지원하는 인코더를 설정 파일의 Encoder 문자열과 매핑합니다.
+var registry = map[string]func() Encoder{
+	"libsvtav1":  func() Encoder { return SVTAV1{} },
+	"svt":        func() Encoder { return SVTAV1{} },
+	"libaom-av1": func() Encoder { return LibAOM{} },
+	"libaom":     func() Encoder { return LibAOM{} },
+	"av1_qsv":    func() Encoder { return QSV{} },
+	"qsv":        func() Encoder { return QSV{} },
+	"av1_nvenc":  func() Encoder { return NVENC{} },
+	"nvenc":      func() Encoder { return NVENC{} },
+	"av1_amf":    func() Encoder { return AMF{} },
+	"amf":        func() Encoder { return AMF{} },
+}
+
+// New는 이름으로 Encoder를 생성합니다. 등록되지 않은 이름이면 오류를 반환합니다.
+func New(name string) (Encoder, error) {
+	factory, ok := registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("알 수 없는 인코더 '%s'", name)
+	}
+	return factory(), nil
+}
+
+// TenBitPixFmt는 sourcePixFmt(ffprobe가 보고한 원본 pix_fmt, 예: "yuv420p10le")가
+// 10비트 포맷이면 encoderName에 맞는 10비트 출력 pix_fmt를 반환합니다. 원본이 10비트가
+// 아니면 ""을 반환하여 호출자가 각 Encoder의 기본값(withDefault)을 그대로 쓰게 합니다.
+// 하드웨어 인코더(QSV/NVENC/AMF)는 소프트웨어 디코더용 10비트 포맷(yuv420p10le)을 받아들이지
+// 않고 p010le를 요구하므로 인코더별로 분기합니다.
+func TenBitPixFmt(encoderName, sourcePixFmt string) string {
+	if !strings.Contains(sourcePixFmt, "10le") && !strings.Contains(sourcePixFmt, "10be") {
+		return ""
+	}
+	switch strings.ToLower(encoderName) {
+	case "av1_qsv", "av1_nvenc", "av1_amf":
+		return "p010le"
+	default:
+		return "yuv420p10le"
+	}
+}
+
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// SVTAV1은 소프트웨어 인코더 libsvtav1입니다.
+type SVTAV1 struct{}
+
+func (SVTAV1) Name() string { return "libsvtav1" }
+
+func (SVTAV1) Args(opts Options) []string {
+	args := []string{
+		"-crf", strconv.Itoa(nonZero(opts.CRF, 30)),
+		"-preset", withDefault(opts.Preset, "6"),
+		"-pix_fmt", withDefault(opts.PixFmt, "yuv420p10le"),
+	}
+	if opts.Tune != "" {
+		args = append(args, "-svtav1-params", "tune="+opts.Tune)
+	}
+	return append(args, opts.ExtraArgs...)
+}
+
+// LibAOM은 레퍼런스 소프트웨어 인코더 libaom-av1입니다. 매우 느리지만 가장 호환성이 좋습니다.
+type LibAOM struct{}
+
+func (LibAOM) Name() string { return "libaom-av1" }
+
+func (LibAOM) Args(opts Options) []string {
+	args := []string{
+		"-crf", strconv.Itoa(nonZero(opts.CRF, 30)),
+		"-b:v", "0",
+		"-cpu-used", withDefault(opts.Preset, "4"),
+		"-pix_fmt", withDefault(opts.PixFmt, "yuv420p10le"),
+	}
+	return append(args, opts.ExtraArgs...)
+}
+
+// QSV는 Intel Quick Sync Video 하드웨어 인코더 av1_qsv입니다.
+type QSV struct{}
+
+func (QSV) Name() string { return "av1_qsv" }
+
+func (QSV) Args(opts Options) []string {
+	args := []string{
+		"-preset", withDefault(opts.Preset, "medium"),
+	}
+	if opts.Bitrate != "" {
+		args = append(args, "-b:v", opts.Bitrate)
+	} else {
+		args = append(args, "-global_quality", strconv.Itoa(nonZero(opts.CRF, 25)))
+	}
+	args = append(args, "-pix_fmt", withDefault(opts.PixFmt, "nv12"))
+	return append(args, opts.ExtraArgs...)
+}
+
+// NVENC는 NVIDIA 하드웨어 인코더 av1_nvenc입니다.
+type NVENC struct{}
+
+func (NVENC) Name() string { return "av1_nvenc" }
+
+func (NVENC) Args(opts Options) []string {
+	args := []string{
+		"-preset", withDefault(opts.Preset, "p5"),
+		"-cq", strconv.Itoa(nonZero(opts.CRF, 28)),
+		"-pix_fmt", withDefault(opts.PixFmt, "yuv420p"),
+	}
+	if opts.Bitrate != "" {
+		args = append(args, "-b:v", opts.Bitrate)
+	}
+	return append(args, opts.ExtraArgs...)
+}
+
+// AMF는 AMD 하드웨어 인코더 av1_amf입니다.
+type AMF struct{}
+
+func (AMF) Name() string { return "av1_amf" }
+
+func (AMF) Args(opts Options) []string {
+	args := []string{
+		"-quality", withDefault(opts.Preset, "balanced"),
+		"-qp_i", strconv.Itoa(nonZero(opts.CRF, 28)),
+		"-pix_fmt", withDefault(opts.PixFmt, "nv12"),
+	}
+	if opts.Bitrate != "" {
+		args = append(args, "-b:v", opts.Bitrate)
+	}
+	return append(args, opts.ExtraArgs...)
+}
+
+func nonZero(v, fallback int) int {
+	if v == 0 {
+		return fallback
+	}
+	return v
+}
+
+// Available은 ffmpegPath의 `-encoders` 출력을 파싱하여 실제로 컴파일되어 있는
+// 인코더 이름(ffmpeg 코덱 이름 기준, 예: "av1_qsv")의 집합을 반환합니다.
+func Available(ffmpegPath string) (map[string]bool, error) {
+	cmd := exec.Command(ffmpegPath, "-hide_banner", "-encoders")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("'%s -encoders' 실행 중 오류 발생: %w", ffmpegPath, err)
+	}
+
+	available := make(map[string]bool)
+	for _, candidate := range []string{"libsvtav1", "libaom-av1", "av1_qsv", "av1_nvenc", "av1_amf"} {
+		if strings.Contains(string(output), " "+candidate+" ") || strings.Contains(string(output), " "+candidate+"\n") {
+			available[candidate] = true
+		}
+	}
+	return available, nil
+}
+
+// Select는 primary 인코더를 사용할 수 있으면 그대로, 아니면 secondary로 대체합니다.
+// 둘 다 사용할 수 없으면 오류를 반환합니다. secondary가 빈 문자열이면 대체를 시도하지 않습니다.
+func Select(ffmpegPath, primary, secondary string) (Encoder, error) {
+	primaryEnc, err := New(primary)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := Available(ffmpegPath)
+	if err != nil {
+		// ffmpeg -encoders 조차 실행할 수 없다면 가용성 확인을 포기하고 primary를 그대로 믿는다.
+		return primaryEnc, nil
+	}
+
+	if available[primaryEnc.Name()] {
+		return primaryEnc, nil
+	}
+
+	if secondary == "" {
+		return nil, fmt.Errorf("인코더 '%s'가 이 ffmpeg 빌드에 포함되어 있지 않고, 대체 인코더(FallbackEncoder)도 설정되어 있지 않습니다", primaryEnc.Name())
+	}
+
+	secondaryEnc, err := New(secondary)
+	if err != nil {
+		return nil, err
+	}
+
+	if !available[secondaryEnc.Name()] {
+		return nil, fmt.Errorf("인코더 '%s'와 대체 인코더 '%s' 모두 이 ffmpeg 빌드에 포함되어 있지 않습니다", primaryEnc.Name(), secondaryEnc.Name())
+	}
+
+	return secondaryEnc, nil
+}
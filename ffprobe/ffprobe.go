@@ -0,0 +1,197 @@
+// Package ffprobe는 ffprobe의 JSON 출력을 구조화된 Go 타입으로 파싱합니다.
+package ffprobe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Tags는 스트림/포맷에 붙은 메타데이터 태그입니다. 필요한 필드만 선별해 옮겨 담습니다.
+type Tags struct {
+	Language string `json:"language"`
+	Title    string `json:"title"`
+}
+
+// Stream은 모든 스트림 타입이 공통으로 갖는 필드입니다.
+type Stream struct {
+	Index     int    `json:"index"`
+	CodecName string `json:"codec_name"`
+	CodecType string `json:"codec_type"`
+	Tags      Tags   `json:"tags"`
+}
+
+// VideoStream은 codec_type이 "video"인 스트림입니다.
+type VideoStream struct {
+	Stream
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	PixFmt     string `json:"pix_fmt"`
+	BitRate    string `json:"bit_rate"`
+	RFrameRate string `json:"r_frame_rate"`
+}
+
+// AudioStream은 codec_type이 "audio"인 스트림입니다.
+type AudioStream struct {
+	Stream
+	SampleRate    string `json:"sample_rate"`
+	Channels      int    `json:"channels"`
+	ChannelLayout string `json:"channel_layout"`
+	BitRate       string `json:"bit_rate"`
+}
+
+// SubtitleStream은 codec_type이 "subtitle"인 스트림입니다.
+type SubtitleStream struct {
+	Stream
+}
+
+// Format은 ffprobe -show_format 출력입니다.
+type Format struct {
+	Filename   string `json:"filename"`
+	FormatName string `json:"format_name"`
+	Duration   string `json:"duration"`
+	Size       string `json:"size"`
+	BitRate    string `json:"bit_rate"`
+}
+
+// rawStream은 ffprobe가 실제로 내려주는 평평한 JSON 스트림 객체로,
+// codec_type에 따라 VideoStream/AudioStream/SubtitleStream으로 분류하기 위해 사용합니다.
+type rawStream struct {
+	Index         int    `json:"index"`
+	CodecName     string `json:"codec_name"`
+	CodecType     string `json:"codec_type"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	PixFmt        string `json:"pix_fmt"`
+	BitRate       string `json:"bit_rate"`
+	RFrameRate    string `json:"r_frame_rate"`
+	SampleRate    string `json:"sample_rate"`
+	Channels      int    `json:"channels"`
+	ChannelLayout string `json:"channel_layout"`
+	Tags          Tags   `json:"tags"`
+}
+
+type probeOutput struct {
+	Streams []rawStream `json:"streams"`
+	Format  Format      `json:"format"`
+}
+
+// FFProbeInfo는 하나의 미디어 파일에 대해 ffprobe가 보고한 정보를 담습니다.
+type FFProbeInfo struct {
+	Format          Format
+	VideoStreams    []VideoStream
+	AudioStreams    []AudioStream
+	SubtitleStreams []SubtitleStream
+}
+
+// PrimaryVideoStream은 첫 번째 비디오 스트림을 반환합니다. 없으면 ok=false입니다.
+func (info *FFProbeInfo) PrimaryVideoStream() (VideoStream, bool) {
+	if len(info.VideoStreams) == 0 {
+		return VideoStream{}, false
+	}
+	return info.VideoStreams[0], true
+}
+
+// resolveFFprobePath는 ffmpeg 실행 파일 경로를 기준으로 같은 디렉토리의 ffprobe를 찾고,
+// 없으면 시스템 PATH의 ffprobe로 대체합니다.
+func resolveFFprobePath(ffmpegPath string) string {
+	ffmpegDir := filepath.Dir(ffmpegPath)
+	ffprobePath := filepath.Join(ffmpegDir, "ffprobe")
+
+	if runtime.GOOS == "windows" {
+		ffprobePath += ".exe"
+	}
+
+	if _, err := os.Stat(ffprobePath); os.IsNotExist(err) {
+		return "ffprobe"
+	}
+
+	return ffprobePath
+}
+
+// CountVideoFrames는 path의 주 비디오 스트림 프레임 수를 ffprobe -count_frames로 셉니다.
+// -show_streams가 보고하는 nb_frames 필드는 컨테이너에 프레임 색인이 없으면(예: 일부 mkv)
+// "N/A"인 경우가 많아 신뢰할 수 없으므로, 실제로 스트림을 끝까지 디코드해 세는 이 함수를
+// 대신 사용합니다. -count_frames는 주 비디오 스트림 전체를 디코드하므로 Probe보다 훨씬
+// 느리며, 길이 기반 검증이 불가능할 때의 대체 수단으로만 써야 합니다.
+func CountVideoFrames(path string, ffmpegPath string) (int64, error) {
+	ffprobePath := resolveFFprobePath(ffmpegPath)
+
+	cmd := exec.Command(ffprobePath,
+		"-v", "quiet",
+		"-select_streams", "v:0",
+		"-count_frames",
+		"-show_entries", "stream=nb_read_frames",
+		"-print_format", "default=nokey=1:noprint_wrappers=1",
+		path,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe 프레임 카운트 중 오류 발생 (파일: %s): %w, 명령어: %s", path, err, cmd.String())
+	}
+
+	frames, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("프레임 카운트 결과('%s')를 파싱할 수 없습니다 (파일: %s): %w", strings.TrimSpace(string(output)), path, err)
+	}
+
+	return frames, nil
+}
+
+// Probe는 path의 미디어 정보를 ffprobe로 조회하여 FFProbeInfo로 반환합니다.
+// ffmpegPath는 같은 디렉토리에 있는 ffprobe를 찾는 데 사용되며, 찾지 못하면 PATH의 ffprobe를 사용합니다.
+func Probe(path string, ffmpegPath string) (*FFProbeInfo, error) {
+	ffprobePath := resolveFFprobePath(ffmpegPath)
+
+	cmd := exec.Command(ffprobePath,
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe 실행 중 오류 발생 (파일: %s): %w, 명령어: %s", path, err, cmd.String())
+	}
+
+	var raw probeOutput
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("ffprobe JSON 파싱 중 오류 발생 (파일: %s): %w", path, err)
+	}
+
+	info := &FFProbeInfo{Format: raw.Format}
+	for _, s := range raw.Streams {
+		base := Stream{Index: s.Index, CodecName: s.CodecName, CodecType: s.CodecType, Tags: s.Tags}
+		switch s.CodecType {
+		case "video":
+			info.VideoStreams = append(info.VideoStreams, VideoStream{
+				Stream:     base,
+				Width:      s.Width,
+				Height:     s.Height,
+				PixFmt:     s.PixFmt,
+				BitRate:    s.BitRate,
+				RFrameRate: s.RFrameRate,
+			})
+		case "audio":
+			info.AudioStreams = append(info.AudioStreams, AudioStream{
+				Stream:        base,
+				SampleRate:    s.SampleRate,
+				Channels:      s.Channels,
+				ChannelLayout: s.ChannelLayout,
+				BitRate:       s.BitRate,
+			})
+		case "subtitle":
+			info.SubtitleStreams = append(info.SubtitleStreams, SubtitleStream{Stream: base})
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/Chahangyu/convert_video_to_av1/ffprobe"
+)
+
+// PartialPath는 finalPath에 대응하는 임시 출력 경로를 만듭니다. ffmpeg는 이 경로에
+// 직접 쓰고, 검증을 통과한 뒤에만 Finalize가 finalPath로 원자적 rename을 수행합니다.
+// 따라서 변환 도중 프로세스가 죽어도 finalPath 자리에는 반쪽짜리 파일이 남지 않습니다.
+func PartialPath(finalPath string) string {
+	return finalPath + ".partial"
+}
+
+// Finalize는 ffmpeg가 partialPath에 성공적으로 다 쓴 뒤 호출합니다. srcPath를 다시
+// ffprobe하여 partialPath와 길이(초) 또는 프레임 수를 비교하고, toleranceFraction(예: 0.02 =
+// 2%) 이내면 정상 변환으로 보고 partialPath를 finalPath로 rename합니다. 차이가 크면
+// partialPath를 지우고 오류를 반환합니다 - 잘못된 결과물을 최종 출력 자리에 남기지 않기
+// 위함입니다. 길이 정보를 양쪽에서 믿을 수 있으면 길이로 비교하고, 그렇지 않으면(일부
+// mkv 출력처럼 Format.Duration이 비어 있는 경우) ffprobe.CountVideoFrames로 실제 프레임
+// 수를 세어 대체 검증합니다. 둘 다 확인할 수 없으면 검증을 건너뛰지 않고 실패로
+// 처리합니다 - 잘린 결과물이 검증을 통과한 것처럼 최종 경로에 놓이면 안 되기 때문입니다.
+func Finalize(ffmpegPath, srcPath, partialPath, finalPath string, toleranceFraction float64) error {
+	srcInfo, err := ffprobe.Probe(srcPath, ffmpegPath)
+	if err != nil {
+		return fmt.Errorf("원본 '%s' 재확인 중 오류 발생: %w", srcPath, err)
+	}
+
+	outInfo, err := ffprobe.Probe(partialPath, ffmpegPath)
+	if err != nil {
+		os.Remove(partialPath)
+		return fmt.Errorf("출력 '%s' 검증 중 오류 발생: %w", partialPath, err)
+	}
+
+	srcDuration, srcDurErr := strconv.ParseFloat(srcInfo.Format.Duration, 64)
+	outDuration, outDurErr := strconv.ParseFloat(outInfo.Format.Duration, 64)
+	if srcDurErr == nil && outDurErr == nil && srcDuration > 0 {
+		diff := math.Abs(srcDuration-outDuration) / srcDuration
+		if diff > toleranceFraction {
+			os.Remove(partialPath)
+			return fmt.Errorf("검증 실패: '%s'의 길이(%.2fs)가 원본(%.2fs)과 %.1f%% 차이나 허용 오차(%.1f%%)를 넘습니다",
+				partialPath, outDuration, srcDuration, diff*100, toleranceFraction*100)
+		}
+		if err := os.Rename(partialPath, finalPath); err != nil {
+			return fmt.Errorf("임시 출력 '%s'를 '%s'로 교체하는 중 오류 발생: %w", partialPath, finalPath, err)
+		}
+		return nil
+	}
+
+	srcFrames, srcFrameErr := ffprobe.CountVideoFrames(srcPath, ffmpegPath)
+	outFrames, outFrameErr := ffprobe.CountVideoFrames(partialPath, ffmpegPath)
+	if srcFrameErr != nil || outFrameErr != nil || srcFrames <= 0 {
+		os.Remove(partialPath)
+		return fmt.Errorf("검증 실패: '%s'의 길이와 프레임 수를 모두 확인할 수 없어 출력을 신뢰할 수 없습니다", partialPath)
+	}
+
+	diff := math.Abs(float64(srcFrames-outFrames)) / float64(srcFrames)
+	if diff > toleranceFraction {
+		os.Remove(partialPath)
+		return fmt.Errorf("검증 실패: '%s'의 프레임 수(%d)가 원본(%d)과 %.1f%% 차이나 허용 오차(%.1f%%)를 넘습니다",
+			partialPath, outFrames, srcFrames, diff*100, toleranceFraction*100)
+	}
+
+	if err := os.Rename(partialPath, finalPath); err != nil {
+		return fmt.Errorf("임시 출력 '%s'를 '%s'로 교체하는 중 오류 발생: %w", partialPath, finalPath, err)
+	}
+
+	return nil
+}
+
+// ReplaceOriginal은 검증을 통과한 뒤 원본 소스 파일을 삭제합니다. 휴지통으로 옮기는 것이
+// 더 안전하지만 표준 라이브러리만으로는 OS별 휴지통 동작을 신뢰성 있게 구현할 수 없으므로,
+// Finalize가 이미 출력물을 검증했다는 전제 하에 단순 삭제로 처리합니다.
+func ReplaceOriginal(srcPath string) error {
+	if err := os.Remove(srcPath); err != nil {
+		return fmt.Errorf("원본 '%s' 삭제 중 오류 발생: %w", srcPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package output은 변환 결과물을 안전하게(원자적으로) 기록하고, 이미 변환된 파일을
+// 추적하여 중단된 작업을 이어서 처리할 수 있게 합니다.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Record는 소스 파일 하나가 언제, 어떤 상태로 변환되었는지를 나타냅니다.
+// Size/ModUnixNano는 다음 실행에서 소스 파일이 그대로인지(덮어써지지 않았는지) 확인하는
+// 데 쓰입니다. ffprobe를 매번 다시 돌리지 않고도 재실행을 저렴하게 만들기 위함입니다.
+type Record struct {
+	Size        int64  `json:"Size"`
+	ModUnixNano int64  `json:"ModUnixNano"`
+	OutputPath  string `json:"OutputPath"`
+}
+
+// StateStore는 변환 완료 기록을 JSON 파일로 유지합니다. 동시에 여러 워커가 접근할 수
+// 있으므로 내부적으로 뮤텍스로 보호합니다.
+type StateStore struct {
+	path string
+
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// LoadStateStore는 path의 상태 파일을 읽어 StateStore를 만듭니다. 파일이 없으면
+// 빈 상태로 시작합니다 (최초 실행).
+func LoadStateStore(path string) (*StateStore, error) {
+	store := &StateStore{path: path, records: make(map[string]Record)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("상태 파일 '%s' 읽기 중 오류 발생: %w", path, err)
+	}
+
+	if len(data) == 0 {
+		return store, nil
+	}
+
+	if err := json.Unmarshal(data, &store.records); err != nil {
+		return nil, fmt.Errorf("상태 파일 '%s' 파싱 중 오류 발생: %w", path, err)
+	}
+
+	return store, nil
+}
+
+// IsConverted는 srcPath가 size/modTime 기준으로 이전에 성공적으로 변환되었는지 확인합니다.
+// 소스 파일의 크기나 수정 시각이 기록과 다르면(다른 파일로 교체된 것으로 보고) false를 반환합니다.
+func (s *StateStore) IsConverted(srcPath string, size int64, modUnixNano int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[srcPath]
+	if !ok {
+		return false
+	}
+	return record.Size == size && record.ModUnixNano == modUnixNano
+}
+
+// MarkConverted는 srcPath의 변환 완료를 기록하고 즉시 디스크에 저장합니다.
+func (s *StateStore) MarkConverted(srcPath string, size int64, modUnixNano int64, outputPath string) error {
+	s.mu.Lock()
+	s.records[srcPath] = Record{Size: size, ModUnixNano: modUnixNano, OutputPath: outputPath}
+	s.mu.Unlock()
+
+	return s.save()
+}
+
+// save는 상태를 path에 원자적으로 기록합니다 (임시 파일에 쓴 뒤 rename).
+// 워커 풀의 여러 고루틴이 MarkConverted를 동시에 호출할 수 있으므로, 다른 호출의 쓰기가
+// 끼어들어 임시 파일이 섞이지 않도록 마샬링부터 rename까지 전부 mu를 쥔 채로 수행합니다.
+func (s *StateStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("상태 직렬화 중 오류 발생: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("임시 상태 파일 '%s' 쓰기 중 오류 발생: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("상태 파일 '%s'로 교체하는 중 오류 발생: %w", s.path, err)
+	}
+	return nil
+}
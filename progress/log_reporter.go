@@ -0,0 +1,50 @@
+package progress
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// LogReporter는 ProgressReporter 구현체로, 파일당 interval 간격으로 한 줄씩만
+// 진행 상황을 로그로 남겨 터미널이 과도한 출력으로 도배되지 않도록 합니다.
+type LogReporter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// NewLogReporter는 파일별로 최소 interval 간격으로 로그를 남기는 LogReporter를 만듭니다.
+func NewLogReporter(interval time.Duration) *LogReporter {
+	return &LogReporter{
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// Report는 ProgressReporter를 만족시킵니다. 이벤트가 끝(Done)이거나 마지막 로그 이후
+// interval이 지났을 때만 실제로 로그를 남깁니다.
+func (r *LogReporter) Report(path string, ev Event) {
+	now := time.Now()
+
+	r.mu.Lock()
+	last, seen := r.last[path]
+	shouldLog := ev.Done || !seen || now.Sub(last) >= r.interval
+	if shouldLog {
+		r.last[path] = now
+	}
+	r.mu.Unlock()
+
+	if !shouldLog {
+		return
+	}
+
+	if ev.Percent >= 0 {
+		log.Printf("진행 중: '%s' %.1f%% (프레임 %d, %.1f fps, 속도 %.2fx, 비트레이트 %s)",
+			path, ev.Percent, ev.Frame, ev.FPS, ev.Speed, ev.Bitrate)
+	} else {
+		log.Printf("진행 중: '%s' 프레임 %d, %.1f fps, 속도 %.2fx, 비트레이트 %s",
+			path, ev.Frame, ev.FPS, ev.Speed, ev.Bitrate)
+	}
+}
@@ -0,0 +1,90 @@
+// Package progress는 ffmpeg의 `-progress` key=value 스트림을 파싱하여
+// 진행률 이벤트로 변환합니다.
+package progress
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Event는 ffmpeg가 -progress로 한 번에 내려보내는 한 블록(frame=...부터
+// progress=continue/end까지)을 담습니다.
+type Event struct {
+	Frame   int64
+	FPS     float64
+	Bitrate string
+	// OutTimeMs는 현재까지 인코딩된 분량(밀리초)입니다. out_time_us 키(마이크로초)를
+	// 1000으로 나누어 계산합니다. out_time_ms 키는 이름과 달리 실제로는 마이크로초
+	// 단위로 찍히는 ffmpeg의 알려진 버그가 있어 사용하지 않습니다.
+	OutTimeMs int64
+	Speed     float64
+	// Done은 이 파일의 인코딩이 끝났음을 의미합니다 (progress=end).
+	Done bool
+	// Percent는 Parse에 전달된 durationMs가 0보다 클 때만 계산되며, 그렇지 않으면 -1입니다.
+	Percent float64
+}
+
+// ProgressReporter는 파일별 진행 이벤트를 전달받아 원하는 방식(로그, 진행 바, HTTP 상태 등)으로
+// 보여주는 프론트엔드가 구현하는 인터페이스입니다.
+type ProgressReporter interface {
+	Report(path string, ev Event)
+}
+
+// ReporterFunc는 일반 함수를 ProgressReporter로 사용할 수 있게 해주는 어댑터입니다.
+type ReporterFunc func(path string, ev Event)
+
+// Report는 ProgressReporter 인터페이스를 만족시키기 위해 f 자신을 호출합니다.
+func (f ReporterFunc) Report(path string, ev Event) {
+	f(path, ev)
+}
+
+// Parse는 ffmpeg를 "-progress pipe:2 -nostats"로 실행했을 때 stderr에 출력되는
+// key=value 줄들을 읽어 블록 단위(progress=continue 또는 progress=end로 끝나는 구간)로
+// Event를 만들어 emit에 전달합니다. durationMs가 0보다 크면 out_time_us(마이크로초)를
+// 밀리초로 환산해 Percent를 계산하고, 그렇지 않으면 Percent는 -1로 둡니다.
+// out_time_ms 키는 이름과 달리 실제로는 마이크로초 단위라 의도적으로 무시합니다.
+//
+// r이 끝에 도달하면(io.EOF) nil을 반환합니다.
+func Parse(r io.Reader, durationMs int64, emit func(Event)) error {
+	scanner := bufio.NewScanner(r)
+	var current Event
+	current.Percent = -1
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "frame":
+			current.Frame, _ = strconv.ParseInt(value, 10, 64)
+		case "fps":
+			current.FPS, _ = strconv.ParseFloat(value, 64)
+		case "bitrate":
+			current.Bitrate = value
+		case "out_time_us":
+			outTimeUs, _ := strconv.ParseInt(value, 10, 64)
+			current.OutTimeMs = outTimeUs / 1000
+			if durationMs > 0 {
+				current.Percent = float64(current.OutTimeMs) / float64(durationMs) * 100
+				if current.Percent > 100 {
+					current.Percent = 100
+				}
+			}
+		case "speed":
+			current.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+		case "progress":
+			current.Done = value == "end"
+			emit(current)
+			current = Event{Percent: -1}
+		}
+	}
+
+	return scanner.Err()
+}